@@ -0,0 +1,37 @@
+// Command vmware-wayland-clipboard-bridge runs the clipbridge library as a
+// standalone daemon, syncing clipboards continuously until interrupted.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/renepanke/vmware-wayland-clipboard-bridge/pkg/clipbridge"
+)
+
+func main() {
+	config, err := clipbridge.LoadConfig()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		_, _ = fmt.Fprintf(os.Stderr, "using defaults...\n")
+		config = clipbridge.DefaultConfig()
+	}
+
+	bridge, err := clipbridge.New(config)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	events := bridge.Watch(ctx)
+	for range events {
+		// Events are logged by the bridge itself; draining the channel here
+		// just keeps it from filling up while the daemon runs unattended.
+	}
+}
@@ -0,0 +1,621 @@
+package clipbridge
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// supportedMimeTypes lists the MIME types the bridge negotiates, in the
+// order they are probed. A provider forwards whichever of these the source
+// side actually advertises.
+var supportedMimeTypes = []string{"text/plain", "text/html", "image/png", "text/uri-list"}
+
+// ClipboardPayload holds the content of a clipboard across every MIME type
+// it was offered in, keyed by MIME type.
+type ClipboardPayload map[string][]byte
+
+// empty reports whether the payload carries no data at all.
+func (p ClipboardPayload) empty() bool {
+	return len(p) == 0
+}
+
+// mimeTypes returns the MIME types present in the payload.
+func (p ClipboardPayload) mimeTypes() []string {
+	types := make([]string, 0, len(p))
+	for mime := range p {
+		types = append(types, mime)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// mimeToX11Target maps a MIME type to the X11 selection target used to
+// fetch/set it; text/plain is requested as UTF8_STRING, the rest map
+// directly onto their MIME name.
+func mimeToX11Target(mime string) string {
+	if mime == "text/plain" {
+		return "UTF8_STRING"
+	}
+	return mime
+}
+
+// splitLines splits command output into non-empty, trimmed lines.
+func splitLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// containsString reports whether needle is present in haystack.
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// clipboardSide identifies which side of the bridge produced a change notification.
+type clipboardSide int
+
+const (
+	sideSource clipboardSide = iota
+	sideSink
+)
+
+// Event reports one clipboard change the bridge observed and, if its
+// content actually differed from the other side, applied there.
+type Event struct {
+	Direction string // "source->sink" or "sink->source"
+	Channel   string // "clipboard" or "primary"
+	MimeTypes []string
+	Hash      string
+}
+
+// Bridge syncs clipboard content between a source and a sink
+// ClipboardProvider.
+type Bridge struct {
+	source           ClipboardProvider
+	sink             ClipboardProvider
+	sourceName       string
+	sinkName         string
+	lastSourceHash   string
+	lastSinkHash     string
+	syncInterval     time.Duration
+	commandTimeout   time.Duration
+	maxClipboardSize int
+	enableLogging    bool
+	logWriter        io.WriteCloser
+	logger           *log.Logger
+	mode             string
+
+	// Primary selection pipeline (X11 PRIMARY / Wayland primary-selection),
+	// tracked and synced independently of the CLIPBOARD pipeline above.
+	primaryEnabled    bool
+	primarySource     ClipboardProvider
+	primarySink       ClipboardProvider
+	lastSourcePrimary string
+	lastSinkPrimary   string
+}
+
+// New creates a Bridge from config.
+func New(config Config) (*Bridge, error) {
+	var logOutput io.WriteCloser = os.Stdout
+
+	if config.Logging.LogFile != "" {
+		// Expand ~ to home directory
+		logPath := os.ExpandEnv(config.Logging.LogFile)
+		if len(logPath) > 0 && logPath[0] == '~' {
+			home, _ := os.UserHomeDir()
+			logPath = filepath.Join(home, logPath[1:])
+		}
+
+		// Ensure directory exists
+		logDir := filepath.Dir(logPath)
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		logOutput = f
+	}
+
+	logger := log.New(logOutput, "[vmware-wayland-clipboard-bridge] ", log.LstdFlags)
+
+	mode := config.Sync.Mode
+	if mode == "" {
+		mode = "poll"
+	}
+
+	sourceName := config.Sync.Source
+	if sourceName == "" {
+		sourceName = "wayland"
+	}
+	sinkName := config.Sync.Sink
+	if sinkName == "" {
+		sinkName = "xclip"
+	}
+	backend := config.Sync.Backend
+	if backend == "" {
+		backend = "cli"
+	}
+
+	b := &Bridge{
+		sourceName:       sourceName,
+		sinkName:         sinkName,
+		syncInterval:     time.Duration(config.Sync.IntervalMs) * time.Millisecond,
+		commandTimeout:   time.Duration(config.Timeouts.CommandTimeout) * time.Second,
+		maxClipboardSize: config.Timeouts.MaxClipboardSize,
+		enableLogging:    config.Logging.Verbose,
+		logWriter:        logOutput,
+		logger:           logger,
+		mode:             mode,
+	}
+
+	if backend == "cli" {
+		for _, name := range []string{sourceName, sinkName} {
+			for _, bin := range requiredBinaries(name) {
+				if _, err := exec.LookPath(bin); err != nil {
+					return nil, fmt.Errorf("%q provider requires %q, which was not found on PATH", name, bin)
+				}
+			}
+		}
+	}
+
+	runner := providerRunner{timeout: b.commandTimeout, maxSize: b.maxClipboardSize, logf: b.logf}
+
+	buildProvider := newProvider
+	if backend == "native" {
+		buildProvider = func(name string, _ CustomProviderConfig, runner providerRunner) (ClipboardProvider, error) {
+			return newNativeProvider(name, runner)
+		}
+	}
+
+	source, err := buildProvider(sourceName, config.Sync.Custom, runner)
+	if err != nil {
+		return nil, fmt.Errorf("sync.source: %w", err)
+	}
+	sink, err := buildProvider(sinkName, config.Sync.Custom, runner)
+	if err != nil {
+		return nil, fmt.Errorf("sync.sink: %w", err)
+	}
+	b.source = source
+	b.sink = sink
+
+	if config.Sync.Primary.Enabled {
+		sourcePrimary, sourceOk := source.(primaryCapable)
+		sinkPrimary, sinkOk := sink.(primaryCapable)
+		if !sourceOk || !sinkOk {
+			b.logf("warning: sync.primary.enabled is set but %s/%s do not support a primary selection, disabling", sourceName, sinkName)
+		} else {
+			b.primaryEnabled = true
+			b.primarySource = sourcePrimary.AsPrimary()
+			b.primarySink = sinkPrimary.AsPrimary()
+		}
+	}
+
+	return b, nil
+}
+
+// Get reads the current clipboard content from the source provider.
+func (b *Bridge) Get() (ClipboardPayload, error) {
+	return b.source.Get(context.Background())
+}
+
+// Set writes payload to the source provider.
+func (b *Bridge) Set(payload ClipboardPayload) error {
+	return b.source.Set(context.Background(), payload)
+}
+
+// hashPayload returns a SHA256 hash of a payload's content, across all
+// MIME types, keyed in sorted order so the hash is independent of
+// enumeration order.
+func (b *Bridge) hashPayload(payload ClipboardPayload) string {
+	keys := make([]string, 0, len(payload))
+	for k := range payload {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write(payload[k])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// Sync performs one on-demand sync cycle between source and sink, and
+// between the primary-selection source and sink if enabled, then returns.
+// Callers that just want a single reconciliation pass without the
+// continuous daemon loop (Watch) can call this directly.
+func (b *Bridge) Sync(ctx context.Context) error {
+	if err := b.syncClipboard(ctx, nil); err != nil {
+		return err
+	}
+	if b.primaryEnabled {
+		return b.syncPrimary(ctx, nil)
+	}
+	return nil
+}
+
+// syncClipboard performs one sync cycle: source <-> sink. If events is
+// non-nil, an Event is sent for every direction that actually applied a
+// change.
+func (b *Bridge) syncClipboard(ctx context.Context, events chan<- Event) error {
+	sourcePayload, _ := b.source.Get(ctx)
+	sinkPayload, _ := b.sink.Get(ctx)
+
+	sourceHash := b.hashPayload(sourcePayload)
+	sinkHash := b.hashPayload(sinkPayload)
+
+	// Sync source -> sink (if source changed)
+	if sourceHash != b.lastSourceHash && !sourcePayload.empty() {
+		if sourceHash != sinkHash { // Only sync if they differ
+			b.logf(">> %s -> %s: %d mime types", b.sourceName, b.sinkName, len(sourcePayload))
+			if err := b.sink.Set(ctx, sourcePayload); err != nil {
+				b.logf("error syncing to %s: %v", b.sinkName, err)
+			}
+			// Some providers (wl-copy/xclip) become the sole selection owner
+			// on every invocation, so writing several MIME types one at a
+			// time can silently evict all but the last. Re-read what the
+			// sink actually ended up holding instead of trusting the
+			// payload we sent, so a partial write is recorded as what it
+			// is and the next cycle doesn't see a "changed" sink and bounce
+			// the partial content straight back over the source.
+			appliedPayload, _ := b.sink.Get(ctx)
+			appliedHash := b.hashPayload(appliedPayload)
+			emit(events, "source->sink", "clipboard", appliedPayload, appliedHash)
+			b.lastSinkHash = appliedHash
+			sinkHash = appliedHash
+		}
+		b.lastSourceHash = sourceHash
+	}
+
+	// Sync sink -> source (if sink changed)
+	if sinkHash != b.lastSinkHash && !sinkPayload.empty() {
+		if sinkHash != sourceHash { // Only sync if they differ
+			b.logf("<< %s -> %s: %d mime types", b.sinkName, b.sourceName, len(sinkPayload))
+			if err := b.source.Set(ctx, sinkPayload); err != nil {
+				b.logf("error syncing to %s: %v", b.sourceName, err)
+			}
+			appliedPayload, _ := b.source.Get(ctx)
+			appliedHash := b.hashPayload(appliedPayload)
+			emit(events, "sink->source", "clipboard", appliedPayload, appliedHash)
+			b.lastSourceHash = appliedHash
+			sourceHash = appliedHash
+		}
+		b.lastSinkHash = sinkHash
+	}
+
+	return nil
+}
+
+// syncPrimary performs one sync cycle for the primary selection pipeline,
+// mirroring syncClipboard but against primarySource/primarySink and their
+// own loop-prevention hashes.
+func (b *Bridge) syncPrimary(ctx context.Context, events chan<- Event) error {
+	sourcePayload, _ := b.primarySource.Get(ctx)
+	sinkPayload, _ := b.primarySink.Get(ctx)
+
+	sourceHash := b.hashPayload(sourcePayload)
+	sinkHash := b.hashPayload(sinkPayload)
+
+	if sourceHash != b.lastSourcePrimary && !sourcePayload.empty() {
+		if sourceHash != sinkHash {
+			b.logf(">> %s -> %s (primary): %d mime types", b.sourceName, b.sinkName, len(sourcePayload))
+			if err := b.primarySink.Set(ctx, sourcePayload); err != nil {
+				b.logf("error syncing primary to %s: %v", b.sinkName, err)
+			}
+			appliedPayload, _ := b.primarySink.Get(ctx)
+			appliedHash := b.hashPayload(appliedPayload)
+			emit(events, "source->sink", "primary", appliedPayload, appliedHash)
+			b.lastSinkPrimary = appliedHash
+			sinkHash = appliedHash
+		}
+		b.lastSourcePrimary = sourceHash
+	}
+
+	if sinkHash != b.lastSinkPrimary && !sinkPayload.empty() {
+		if sinkHash != sourceHash {
+			b.logf("<< %s -> %s (primary): %d mime types", b.sinkName, b.sourceName, len(sinkPayload))
+			if err := b.primarySource.Set(ctx, sinkPayload); err != nil {
+				b.logf("error syncing primary to %s: %v", b.sourceName, err)
+			}
+			appliedPayload, _ := b.primarySource.Get(ctx)
+			appliedHash := b.hashPayload(appliedPayload)
+			emit(events, "sink->source", "primary", appliedPayload, appliedHash)
+			b.lastSourcePrimary = appliedHash
+			sourceHash = appliedHash
+		}
+		b.lastSinkPrimary = sinkHash
+	}
+
+	return nil
+}
+
+// emit sends an Event for a payload that was just applied, if events is
+// non-nil. Sends are best-effort: a full channel drops the notification
+// rather than blocking the sync loop.
+func emit(events chan<- Event, direction, channel string, payload ClipboardPayload, hash string) {
+	if events == nil {
+		return
+	}
+	select {
+	case events <- Event{Direction: direction, Channel: channel, MimeTypes: payload.mimeTypes(), Hash: hash}:
+	default:
+	}
+}
+
+// Watch starts the continuous sync loop in the background — event-driven
+// (watch mode) if configured and supported, fixed-interval polling
+// otherwise — and returns a channel of Events describing every change it
+// applies. The loop, and the primary-selection pipeline alongside it if
+// enabled, stop and the channel is closed when ctx is cancelled.
+func (b *Bridge) Watch(ctx context.Context) <-chan Event {
+	events := make(chan Event, 16)
+
+	go func() {
+		defer close(events)
+
+		var wg sync.WaitGroup
+		if b.primaryEnabled {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b.watchPrimaryPoll(ctx, events)
+			}()
+		}
+
+		if b.mode == "watch" && b.supportsWatchMode() {
+			b.watchMode(ctx, events)
+		} else {
+			if b.mode == "watch" {
+				b.logf("warning: watch mode requested but not supported for %s/%s, falling back to poll mode", b.sourceName, b.sinkName)
+			}
+			b.pollMode(ctx, events)
+		}
+
+		// watchPrimaryPoll runs concurrently and logs through the same
+		// logWriter, so wait for it to exit before shutdown() closes the
+		// log file out from under it.
+		wg.Wait()
+		b.shutdown()
+	}()
+
+	return events
+}
+
+// supportsWatchMode reports whether the configured source/sink pair has a
+// watch-mode implementation and the underlying tools support it. Only the
+// default wayland/xclip pair currently does.
+func (b *Bridge) supportsWatchMode() bool {
+	if b.sourceName != "wayland" || b.sinkName != "xclip" {
+		return false
+	}
+	if out, err := exec.Command("wl-paste", "--help").CombinedOutput(); err != nil || !bytes.Contains(out, []byte("--watch")) {
+		return false
+	}
+	return xclipSupportsWatch()
+}
+
+// xclipSupportsWatch probes whether this xclip build actually understands
+// `-o -w` by starting it for real, rather than checking `-version` output:
+// every xclip build ever released prints "xclip" in its version string, so
+// that substring match is trivially true even for builds that predate the
+// watch flag. A build that doesn't recognize -w rejects the command line
+// and exits (almost always nonzero) well within the probe window; a build
+// that does accept it blocks waiting for a clipboard change, exactly like
+// watchSink's long-lived invocation relies on, so it's still running when
+// the window elapses.
+func xclipSupportsWatch() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "xclip", "-selection", "clipboard", "-o", "-w")
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	select {
+	case <-done:
+		return false
+	case <-ctx.Done():
+		_ = cmd.Process.Kill()
+		<-done
+		return true
+	}
+}
+
+// pollMode runs the original fixed-interval ticker loop until ctx is cancelled.
+func (b *Bridge) pollMode(ctx context.Context, events chan<- Event) {
+	ticker := time.NewTicker(b.syncInterval)
+	defer ticker.Stop()
+
+	b.logf("clipboard sync started in poll mode (interval: %v, timeout: %v, max size: %d bytes)",
+		b.syncInterval, b.commandTimeout, b.maxClipboardSize)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.syncClipboard(ctx, events); err != nil {
+				b.logf("sync error: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchMode spawns long-lived `wl-paste --watch` and `xclip -o -w` listener
+// processes and reacts to their change notifications via a single
+// reconciler goroutine, instead of waking up on a fixed interval.
+func (b *Bridge) watchMode(ctx context.Context, events chan<- Event) {
+	changes := make(chan clipboardSide, 16)
+
+	go b.watchSource(ctx, changes)
+	go b.watchSink(ctx, changes)
+
+	b.logf("clipboard sync started in watch mode (timeout: %v, max size: %d bytes)",
+		b.commandTimeout, b.maxClipboardSize)
+
+	for {
+		select {
+		case side := <-changes:
+			if err := b.reconcile(ctx, side, events); err != nil {
+				b.logf("sync error: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchPrimaryPoll runs an independent fixed-interval loop for the primary
+// selection pipeline, in parallel with whichever mode the CLIPBOARD
+// pipeline uses, until ctx is cancelled.
+func (b *Bridge) watchPrimaryPoll(ctx context.Context, events chan<- Event) {
+	ticker := time.NewTicker(b.syncInterval)
+	defer ticker.Stop()
+
+	b.logf("primary selection sync started (%s <-> %s)", b.sourceName, b.sinkName)
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.syncPrimary(ctx, events); err != nil {
+				b.logf("primary sync error: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchSource runs `wl-paste --watch` with a marker command and emits a
+// sideSource notification each time the Wayland clipboard changes.
+func (b *Bridge) watchSource(ctx context.Context, changes chan<- clipboardSide) {
+	cmd := exec.CommandContext(ctx, "wl-paste", "--watch", "printf", "%s\\n", "changed")
+	b.runWatcher(ctx, cmd, changes, sideSource)
+}
+
+// watchSink runs `xclip -selection clipboard -o -w` and emits a sideSink
+// notification each time the X11 clipboard selection changes.
+func (b *Bridge) watchSink(ctx context.Context, changes chan<- clipboardSide) {
+	cmd := exec.CommandContext(ctx, "xclip", "-selection", "clipboard", "-o", "-w")
+	b.runWatcher(ctx, cmd, changes, sideSink)
+}
+
+// runWatcher starts the given long-lived watcher command, emitting a
+// notification on every line it prints, and restarts it on unexpected exit
+// until ctx is cancelled.
+func (b *Bridge) runWatcher(ctx context.Context, cmd *exec.Cmd, changes chan<- clipboardSide, side clipboardSide) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		b.logf("warning: failed to attach watcher pipe: %v", err)
+		return
+	}
+
+	if err := cmd.Start(); err != nil {
+		b.logf("warning: failed to start watcher %q: %v", cmd.Path, err)
+		return
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		select {
+		case changes <- side:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	_ = cmd.Wait()
+	if ctx.Err() == nil {
+		b.logf("warning: watcher %q exited unexpectedly, restarting", cmd.Path)
+		time.Sleep(time.Second)
+		b.runWatcher(ctx, exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...), changes, side)
+	}
+}
+
+// reconcile performs one sync cycle in response to a change notification
+// from a single side, still relying on lastSourceHash/lastSinkHash to
+// prevent sync loops.
+func (b *Bridge) reconcile(ctx context.Context, side clipboardSide, events chan<- Event) error {
+	switch side {
+	case sideSource:
+		sourcePayload, _ := b.source.Get(ctx)
+		sourceHash := b.hashPayload(sourcePayload)
+		if sourceHash != b.lastSourceHash && !sourcePayload.empty() {
+			if sourceHash != b.lastSinkHash {
+				b.logf(">> %s -> %s: %d mime types", b.sourceName, b.sinkName, len(sourcePayload))
+				if err := b.sink.Set(ctx, sourcePayload); err != nil {
+					b.logf("error syncing to %s: %v", b.sinkName, err)
+				}
+				appliedPayload, _ := b.sink.Get(ctx)
+				appliedHash := b.hashPayload(appliedPayload)
+				emit(events, "source->sink", "clipboard", appliedPayload, appliedHash)
+				b.lastSinkHash = appliedHash
+			}
+			b.lastSourceHash = sourceHash
+		}
+	case sideSink:
+		sinkPayload, _ := b.sink.Get(ctx)
+		sinkHash := b.hashPayload(sinkPayload)
+		if sinkHash != b.lastSinkHash && !sinkPayload.empty() {
+			if sinkHash != b.lastSourceHash {
+				b.logf("<< %s -> %s: %d mime types", b.sinkName, b.sourceName, len(sinkPayload))
+				if err := b.source.Set(ctx, sinkPayload); err != nil {
+					b.logf("error syncing to %s: %v", b.sourceName, err)
+				}
+				appliedPayload, _ := b.source.Get(ctx)
+				appliedHash := b.hashPayload(appliedPayload)
+				emit(events, "sink->source", "clipboard", appliedPayload, appliedHash)
+				b.lastSourceHash = appliedHash
+			}
+			b.lastSinkHash = sinkHash
+		}
+	}
+	return nil
+}
+
+// shutdown logs the stop event and closes the log file, if any.
+func (b *Bridge) shutdown() {
+	b.logf("clipboard sync stopped")
+	if b.logWriter != os.Stdout {
+		if err := b.logWriter.Close(); err != nil {
+			b.logf("warning: error closing log file: %v", err)
+		}
+	}
+}
+
+// logf logs a formatted message if verbose is enabled
+func (b *Bridge) logf(format string, v ...interface{}) {
+	if b.enableLogging {
+		b.logger.Printf(format, v...)
+	}
+}
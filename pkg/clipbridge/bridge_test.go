@@ -0,0 +1,100 @@
+package clipbridge
+
+import (
+	"context"
+	"testing"
+)
+
+// mockProvider is a ClipboardProvider test double. When dropAllButOneOnSet
+// is set, Set mimics wl-copy/xclip: each invocation becomes the sole
+// selection owner, so only the configured mime type actually survives,
+// exactly like the real CLI backends do when asked to carry several MIME
+// types at once.
+type mockProvider struct {
+	payload ClipboardPayload
+
+	dropAllButOneOnSet bool
+	keepMime           string
+
+	setCalls int
+}
+
+func (p *mockProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	return p.payload, nil
+}
+
+func (p *mockProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	p.setCalls++
+	if p.dropAllButOneOnSet {
+		p.payload = ClipboardPayload{p.keepMime: payload[p.keepMime]}
+		return nil
+	}
+	p.payload = payload
+	return nil
+}
+
+func TestSyncClipboard_AppliesSourceChangeToSink(t *testing.T) {
+	source := &mockProvider{payload: ClipboardPayload{"text/plain": []byte("hello")}}
+	sink := &mockProvider{}
+	b := &Bridge{source: source, sink: sink, sourceName: "source", sinkName: "sink"}
+
+	if err := b.syncClipboard(context.Background(), nil); err != nil {
+		t.Fatalf("syncClipboard: %v", err)
+	}
+
+	if sink.setCalls != 1 {
+		t.Fatalf("sink.setCalls = %d, want 1", sink.setCalls)
+	}
+	if string(sink.payload["text/plain"]) != "hello" {
+		t.Fatalf("sink payload = %v, want text/plain=hello", sink.payload)
+	}
+
+	// A second cycle with nothing new on either side should be a no-op.
+	if err := b.syncClipboard(context.Background(), nil); err != nil {
+		t.Fatalf("syncClipboard (second cycle): %v", err)
+	}
+	if sink.setCalls != 1 {
+		t.Fatalf("sink.setCalls after idle cycle = %d, want 1", sink.setCalls)
+	}
+	if source.setCalls != 0 {
+		t.Fatalf("source.setCalls after idle cycle = %d, want 0", source.setCalls)
+	}
+}
+
+// TestSyncClipboard_PartialSinkWriteDoesNotOscillate covers the bug where a
+// sink that can only durably hold one MIME type per Set (wl-copy/xclip) lost
+// every type but the last, while the bridge kept recording the hash of the
+// full outgoing payload as if it had all landed. That mismatch made the
+// very next cycle see the sink "change" back to its real (partial) content
+// and bounce it over the source, clobbering the richer payload that had
+// just synced. syncClipboard must record what the sink actually ended up
+// holding, not what source.Get returned before the write.
+func TestSyncClipboard_PartialSinkWriteDoesNotOscillate(t *testing.T) {
+	source := &mockProvider{payload: ClipboardPayload{
+		"text/plain": []byte("hello"),
+		"text/html":  []byte("<b>hello</b>"),
+	}}
+	sink := &mockProvider{dropAllButOneOnSet: true, keepMime: "text/html"}
+	b := &Bridge{source: source, sink: sink, sourceName: "source", sinkName: "sink"}
+
+	if err := b.syncClipboard(context.Background(), nil); err != nil {
+		t.Fatalf("syncClipboard: %v", err)
+	}
+
+	if len(sink.payload) != 1 {
+		t.Fatalf("sink.payload = %v, want only text/html to have survived", sink.payload)
+	}
+	wantHash := b.hashPayload(ClipboardPayload{"text/html": []byte("<b>hello</b>")})
+	if b.lastSinkHash != wantHash {
+		t.Fatalf("lastSinkHash = %q, want %q (the sink's actual content, not the full source payload)", b.lastSinkHash, wantHash)
+	}
+
+	// The sink's real (partial) content must not look like a new change on
+	// the next cycle and bounce back over the source.
+	if err := b.syncClipboard(context.Background(), nil); err != nil {
+		t.Fatalf("syncClipboard (second cycle): %v", err)
+	}
+	if source.setCalls != 0 {
+		t.Fatalf("source.setCalls = %d, want 0 (partial sink content must not clobber the source)", source.setCalls)
+	}
+}
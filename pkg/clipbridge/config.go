@@ -0,0 +1,112 @@
+// Package clipbridge syncs clipboard content between two ClipboardProvider
+// endpoints (by default the Wayland clipboard and the X11 CLIPBOARD
+// selection inside a VMware guest), and can be embedded by other Go tools
+// instead of run as a standalone daemon.
+package clipbridge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Config represents the configuration file structure
+type Config struct {
+	Timeouts Timeouts      `toml:"timeouts"`
+	Sync     SyncConfig    `toml:"sync"`
+	Logging  LoggingConfig `toml:"logging"`
+}
+
+type Timeouts struct {
+	CommandTimeout   int `toml:"command_timeout"`    // seconds
+	MaxClipboardSize int `toml:"max_clipboard_size"` // bytes
+}
+
+type SyncConfig struct {
+	IntervalMs    int                  `toml:"interval_ms"`
+	EnableLogging bool                 `toml:"enable_logging"`
+	Mode          string               `toml:"mode"`    // "watch" (event-driven) or "poll" (fixed-interval); defaults to "poll"
+	Source        string               `toml:"source"`  // clipboard provider to read from; defaults to "wayland"
+	Sink          string               `toml:"sink"`    // clipboard provider to write to; defaults to "xclip"
+	Custom        CustomProviderConfig `toml:"custom"`  // used when source/sink is "custom"
+	Primary       PrimaryConfig        `toml:"primary"` // opt-in PRIMARY / primary-selection sync
+	Backend       string               `toml:"backend"` // "cli" (shell out to wl-paste/wl-copy/xclip) or "native"; defaults to "cli"
+}
+
+// PrimaryConfig opts into syncing the X11 PRIMARY selection and the
+// Wayland primary-selection protocol (middle-click paste), alongside the
+// regular CLIPBOARD sync.
+type PrimaryConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// CustomProviderConfig configures the "custom" provider: arbitrary
+// yank/paste commands, given as a command name followed by its arguments.
+type CustomProviderConfig struct {
+	GetCmd []string `toml:"get_cmd"`
+	SetCmd []string `toml:"set_cmd"`
+}
+
+type LoggingConfig struct {
+	Verbose bool   `toml:"verbose"`
+	LogFile string `toml:"log_file"` // empty = stdout
+}
+
+// LoadConfig loads the configuration from file or returns defaults
+func LoadConfig() (Config, error) {
+	// Try multiple config paths
+	home, _ := os.UserHomeDir()
+	configPaths := []string{
+		filepath.Join(home, ".config/vmware-sway-sync/config.toml"),
+		filepath.Join(home, ".vmware-sway-sync.toml"),
+		"/etc/vmware-sway-sync/config.toml",
+	}
+
+	var config Config
+	var configPath string
+
+	// Look for existing config
+	for _, path := range configPaths {
+		if _, err := os.Stat(path); err == nil {
+			configPath = path
+			break
+		}
+	}
+
+	if configPath != "" {
+		_, err := toml.DecodeFile(configPath, &config)
+		if err != nil {
+			return Config{}, fmt.Errorf("failed to parse config: %w", err)
+		}
+	} else {
+		// Use defaults if no config file found
+		config = DefaultConfig()
+	}
+
+	return config, nil
+}
+
+// DefaultConfig returns the configuration used when no config file is found.
+func DefaultConfig() Config {
+	home, _ := os.UserHomeDir()
+	return Config{
+		Timeouts: Timeouts{
+			CommandTimeout:   2,
+			MaxClipboardSize: 52428800, // 50MB
+		},
+		Sync: SyncConfig{
+			IntervalMs:    500,
+			EnableLogging: true,
+			Mode:          "poll",
+			Source:        "wayland",
+			Sink:          "xclip",
+			Backend:       "cli",
+		},
+		Logging: LoggingConfig{
+			Verbose: true,
+			LogFile: filepath.Join(home, ".local/share/vmware-sway-sync/sync.log"),
+		},
+	}
+}
@@ -0,0 +1,379 @@
+//go:build native
+
+// The native backend talks to X11 directly via XCB/XFIXES instead of
+// shelling out to xclip, registering as the real CLIPBOARD selection owner
+// so reads are a ConvertSelection round trip instead of a process spawn and
+// ownership changes are observed through XFIXES rather than polling. It
+// only carries text/plain for now; sync.backend stays "cli" by default,
+// which remains the only multi-MIME path. There is no native Wayland
+// backend yet (see newNativeWaylandProvider) — zwlr_data_control_manager_v1
+// needs generated protocol bindings this package doesn't have, so
+// sync.backend = "native" with a wayland provider fails at construction
+// instead of silently no-op-ing.
+package clipbridge
+
+/*
+#cgo pkg-config: xcb xcb-xfixes
+#include <xcb/xcb.h>
+#include <xcb/xfixes.h>
+#include <stdlib.h>
+#include <string.h>
+
+static xcb_connection_t *native_xcb_connect(int *screen_num) {
+	return xcb_connect(NULL, screen_num);
+}
+
+static xcb_window_t native_xcb_root(xcb_connection_t *conn, int screen_num) {
+	const xcb_setup_t *setup = xcb_get_setup(conn);
+	xcb_screen_iterator_t it = xcb_setup_roots_iterator(setup);
+	for (int i = 0; i < screen_num; i++) {
+		xcb_screen_next(&it);
+	}
+	return it.data->root;
+}
+
+static xcb_window_t native_xcb_selection_window(xcb_connection_t *conn, xcb_window_t root) {
+	xcb_window_t win = xcb_generate_id(conn);
+	uint32_t mask = XCB_CW_EVENT_MASK;
+	uint32_t values[1] = {XCB_EVENT_MASK_PROPERTY_CHANGE};
+	xcb_create_window(conn, XCB_COPY_FROM_PARENT, win, root, 0, 0, 1, 1, 0,
+		XCB_WINDOW_CLASS_INPUT_ONLY, XCB_COPY_FROM_PARENT, mask, values);
+	xcb_flush(conn);
+	return win;
+}
+
+// native_xcb_xfixes_init negotiates the XFIXES extension version (required
+// before any other XFIXES call) and returns the extension's first event
+// code, or 0 if XFIXES isn't available.
+static uint8_t native_xcb_xfixes_init(xcb_connection_t *conn) {
+	xcb_xfixes_query_version_cookie_t cookie =
+		xcb_xfixes_query_version(conn, XCB_XFIXES_MAJOR_VERSION, XCB_XFIXES_MINOR_VERSION);
+	xcb_xfixes_query_version_reply_t *reply = xcb_xfixes_query_version_reply(conn, cookie, NULL);
+	if (reply == NULL) {
+		return 0;
+	}
+	free(reply);
+	const xcb_query_extension_reply_t *ext = xcb_get_extension_data(conn, &xcb_xfixes_id);
+	if (ext == NULL) {
+		return 0;
+	}
+	return ext->first_event;
+}
+
+static void native_xcb_watch_selection(xcb_connection_t *conn, xcb_window_t win, xcb_atom_t selection_atom) {
+	xcb_xfixes_select_selection_input(conn, win, selection_atom,
+		XCB_XFIXES_SELECTION_EVENT_MASK_SET_SELECTION_OWNER);
+	xcb_flush(conn);
+}
+
+// native_xcb_send_selection_notify replies to a SelectionRequest, completing
+// the ICCCM handshake; property should be XCB_ATOM_NONE to signal refusal.
+static void native_xcb_send_selection_notify(xcb_connection_t *conn, xcb_window_t requestor,
+		xcb_atom_t selection, xcb_atom_t target, xcb_atom_t property, xcb_timestamp_t time) {
+	xcb_selection_notify_event_t ev;
+	memset(&ev, 0, sizeof(ev));
+	ev.response_type = XCB_SELECTION_NOTIFY;
+	ev.time = time;
+	ev.requestor = requestor;
+	ev.selection = selection;
+	ev.target = target;
+	ev.property = property;
+	xcb_send_event(conn, 0, requestor, XCB_EVENT_MASK_NO_EVENT, (const char *)&ev);
+	xcb_flush(conn);
+}
+*/
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// newNativeProvider builds the cgo-backed provider for name. Only "xclip"
+// (the X11 CLIPBOARD selection) is supported natively today; "wayland" has
+// no native-protocol equivalent yet, and tmux/osc52/custom have none at all,
+// so all of those stay on the CLI backend.
+func newNativeProvider(name string, runner providerRunner) (ClipboardProvider, error) {
+	switch name {
+	case "wayland":
+		return newNativeWaylandProvider(runner)
+	case "xclip":
+		return newNativeX11Provider(runner)
+	default:
+		return nil, fmt.Errorf("native backend does not support provider %q (use sync.backend = \"cli\")", name)
+	}
+}
+
+// convertSelectionProps is the number of distinct property atoms Get cycles
+// through when issuing ConvertSelection requests. Each in-flight request
+// gets its own atom so a reply that arrives after its Get call already timed
+// out can be matched to the (now-absent) request it actually answers,
+// instead of being handed to whichever Get call happens to be waiting next.
+const convertSelectionProps = 8
+
+// nativeX11Provider owns the X11 CLIPBOARD selection directly via XCB: Get
+// issues a ConvertSelection and reads back the resulting property, Set takes
+// ownership and an event-loop goroutine answers other clients'
+// SelectionRequests, and XFIXES reports ownership changes without polling.
+type nativeX11Provider struct {
+	providerRunner
+	conn *C.xcb_connection_t
+	win  C.xcb_window_t
+
+	clipboardAtom C.xcb_atom_t
+	utf8Atom      C.xcb_atom_t
+	targetsAtom   C.xcb_atom_t
+	xfixesEvent   C.uint8_t
+
+	convertProps [convertSelectionProps]C.xcb_atom_t
+	propSeq      uint64
+
+	mu      sync.Mutex
+	held    []byte                       // content we're advertising while we own the selection
+	pending map[C.xcb_atom_t]chan []byte // in-flight ConvertSelection requests, keyed by the property atom tagging each one
+}
+
+func newNativeX11Provider(runner providerRunner) (*nativeX11Provider, error) {
+	var screenNum C.int
+	conn := C.native_xcb_connect(&screenNum)
+	if C.xcb_connection_has_error(conn) != 0 {
+		return nil, errors.New("failed to connect to the X server for the native backend")
+	}
+
+	root := C.native_xcb_root(conn, screenNum)
+	win := C.native_xcb_selection_window(conn, root)
+
+	clipboardAtom, err := internAtom(conn, "CLIPBOARD")
+	if err != nil {
+		C.xcb_disconnect(conn)
+		return nil, err
+	}
+	utf8Atom, err := internAtom(conn, "UTF8_STRING")
+	if err != nil {
+		C.xcb_disconnect(conn)
+		return nil, err
+	}
+	targetsAtom, err := internAtom(conn, "TARGETS")
+	if err != nil {
+		C.xcb_disconnect(conn)
+		return nil, err
+	}
+
+	var convertProps [convertSelectionProps]C.xcb_atom_t
+	for i := range convertProps {
+		atom, err := internAtom(conn, fmt.Sprintf("BRIDGE_SELECTION_%d", i))
+		if err != nil {
+			C.xcb_disconnect(conn)
+			return nil, err
+		}
+		convertProps[i] = atom
+	}
+
+	xfixesEvent := C.native_xcb_xfixes_init(conn)
+	C.native_xcb_watch_selection(conn, win, clipboardAtom)
+
+	p := &nativeX11Provider{
+		providerRunner: runner,
+		conn:           conn,
+		win:            win,
+		clipboardAtom:  clipboardAtom,
+		utf8Atom:       utf8Atom,
+		targetsAtom:    targetsAtom,
+		xfixesEvent:    xfixesEvent,
+		convertProps:   convertProps,
+		pending:        make(map[C.xcb_atom_t]chan []byte),
+	}
+	go p.eventLoop()
+	return p, nil
+}
+
+func internAtom(conn *C.xcb_connection_t, name string) (C.xcb_atom_t, error) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	cookie := C.xcb_intern_atom(conn, 0, C.uint16_t(len(name)), cname)
+	reply := C.xcb_intern_atom_reply(conn, cookie, nil)
+	if reply == nil {
+		return 0, fmt.Errorf("failed to intern X11 atom %q", name)
+	}
+	defer C.free(unsafe.Pointer(reply))
+	return reply.atom, nil
+}
+
+// eventLoop blocks on the XCB connection, serving SelectionRequests while we
+// own the clipboard, delivering ConvertSelection replies to Get, and logging
+// XFIXES ownership-change notifications — for as long as the process runs.
+func (p *nativeX11Provider) eventLoop() {
+	for {
+		ev := C.xcb_wait_for_event(p.conn)
+		if ev == nil {
+			return // connection broken or closed
+		}
+		p.handleEvent(ev)
+		C.free(unsafe.Pointer(ev))
+	}
+}
+
+func (p *nativeX11Provider) handleEvent(ev *C.xcb_generic_event_t) {
+	responseType := ev.response_type & 0x7f
+	switch responseType {
+	case C.XCB_SELECTION_NOTIFY:
+		p.deliverSelectionNotify((*C.xcb_selection_notify_event_t)(unsafe.Pointer(ev)))
+	case C.XCB_SELECTION_REQUEST:
+		p.serveSelectionRequest((*C.xcb_selection_request_event_t)(unsafe.Pointer(ev)))
+	default:
+		if p.xfixesEvent != 0 && responseType == p.xfixesEvent+C.XCB_XFIXES_SELECTION_NOTIFY {
+			p.logf("native x11: clipboard selection owner changed")
+		}
+	}
+}
+
+// deliverSelectionNotify reads back the property our ConvertSelection
+// targeted and hands the bytes to the specific Get call that requested it.
+// A refused conversion reports property == None, which isn't one of our
+// tagged atoms, so there's nothing to correlate it to and it's dropped —
+// the Get call it belongs to still times out on its own, same as before.
+func (p *nativeX11Provider) deliverSelectionNotify(e *C.xcb_selection_notify_event_t) {
+	if e.property == C.XCB_ATOM_NONE {
+		return
+	}
+
+	p.mu.Lock()
+	reply, ok := p.pending[e.property]
+	if ok {
+		delete(p.pending, e.property)
+	}
+	p.mu.Unlock()
+	if !ok {
+		return // no Get is still waiting on this property; a late reply past its deadline
+	}
+
+	select {
+	case reply <- p.getProperty(e.property):
+	default:
+	}
+}
+
+func (p *nativeX11Provider) getProperty(prop C.xcb_atom_t) []byte {
+	cookie := C.xcb_get_property(p.conn, 0, p.win, prop, C.XCB_GET_PROPERTY_TYPE_ANY, 0, 1<<20)
+	reply := C.xcb_get_property_reply(p.conn, cookie, nil)
+	if reply == nil {
+		return nil
+	}
+	defer C.free(unsafe.Pointer(reply))
+
+	length := C.xcb_get_property_value_length(reply)
+	if length == 0 {
+		return nil
+	}
+	value := C.xcb_get_property_value(reply)
+	return C.GoBytes(value, length)
+}
+
+// serveSelectionRequest answers another client's paste request while we own
+// the CLIPBOARD selection: TARGETS gets our supported atom list, UTF8_STRING
+// gets the held content, anything else is refused per ICCCM.
+func (p *nativeX11Provider) serveSelectionRequest(e *C.xcb_selection_request_event_t) {
+	property := e.property
+	if property == C.XCB_ATOM_NONE {
+		property = e.target // legacy requestors expect the target name as the property
+	}
+
+	switch e.target {
+	case p.targetsAtom:
+		targets := [2]C.xcb_atom_t{p.utf8Atom, p.targetsAtom}
+		C.xcb_change_property(p.conn, C.XCB_PROP_MODE_REPLACE, e.requestor, property,
+			C.XCB_ATOM_ATOM, 32, 2, unsafe.Pointer(&targets[0]))
+	case p.utf8Atom:
+		p.mu.Lock()
+		content := p.held
+		p.mu.Unlock()
+		if content == nil {
+			property = C.XCB_ATOM_NONE
+			break
+		}
+		C.xcb_change_property(p.conn, C.XCB_PROP_MODE_REPLACE, e.requestor, property,
+			p.utf8Atom, 8, C.uint32_t(len(content)), unsafe.Pointer(&content[0]))
+	default:
+		property = C.XCB_ATOM_NONE
+	}
+
+	C.native_xcb_send_selection_notify(p.conn, e.requestor, e.selection, e.target, property, e.time)
+}
+
+// Get issues a ConvertSelection against whoever currently owns CLIPBOARD and
+// waits for the resulting property, rather than returning only content this
+// process previously set. Each call is tagged with its own property atom
+// (see convertSelectionProps) so a reply that arrives after this call's
+// deadline can't be mistaken for the answer to any of the next
+// convertSelectionProps-1 calls; the atoms are reused after that.
+func (p *nativeX11Provider) Get(ctx context.Context) (ClipboardPayload, error) {
+	prop := p.convertProps[atomic.AddUint64(&p.propSeq, 1)%convertSelectionProps]
+	reply := make(chan []byte, 1)
+
+	p.mu.Lock()
+	p.pending[prop] = reply
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, prop)
+		p.mu.Unlock()
+	}()
+
+	C.xcb_delete_property(p.conn, p.win, prop)
+	C.xcb_convert_selection(p.conn, p.win, p.clipboardAtom, p.utf8Atom, prop, C.XCB_CURRENT_TIME)
+	C.xcb_flush(p.conn)
+
+	select {
+	case content := <-reply:
+		if len(content) == 0 {
+			return nil, nil
+		}
+		if len(content) > p.maxSize {
+			p.logf("warning: native x11 clipboard exceeds size limit (%d > %d bytes)", len(content), p.maxSize)
+			return nil, nil
+		}
+		return ClipboardPayload{"text/plain": content}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(p.timeout):
+		return nil, nil // nobody owns CLIPBOARD, or they didn't answer in time
+	}
+}
+
+// Set takes ownership of the CLIPBOARD selection and remembers content so
+// the event-loop goroutine can serve it to other clients' SelectionRequests.
+func (p *nativeX11Provider) Set(ctx context.Context, payload ClipboardPayload) error {
+	content, ok := payload["text/plain"]
+	if !ok {
+		return nil
+	}
+	if len(content) > p.maxSize {
+		p.logf("error: content exceeds max size, skipping native x11 sync (%d > %d bytes)", len(content), p.maxSize)
+		return nil
+	}
+
+	p.mu.Lock()
+	p.held = content
+	p.mu.Unlock()
+
+	C.xcb_set_selection_owner(p.conn, p.win, p.clipboardAtom, C.XCB_CURRENT_TIME)
+	C.xcb_flush(p.conn)
+	return nil
+}
+
+// newNativeWaylandProvider is unimplemented: owning the Wayland clipboard
+// without shelling out needs the zwlr_data_control_manager_v1 protocol
+// (core wl_data_device requires keyboard focus, which a headless bridge
+// never has), and that protocol needs wayland-scanner-generated bindings
+// this package doesn't ship. Rather than register a provider whose Get/Set
+// silently do nothing, fail at construction so sync.backend = "native" with
+// a wayland source/sink is a clear configuration error.
+func newNativeWaylandProvider(runner providerRunner) (ClipboardProvider, error) {
+	return nil, errors.New("native backend does not support provider \"wayland\" yet (needs zwlr_data_control_manager_v1 bindings); use sync.backend = \"cli\" for wayland")
+}
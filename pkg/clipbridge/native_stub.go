@@ -0,0 +1,12 @@
+//go:build !native
+
+package clipbridge
+
+import "fmt"
+
+// newNativeProvider is only available in binaries built with `-tags native`
+// (which links against libxcb/libxcb-xfixes via cgo). Without that tag,
+// sync.backend = "native" fails fast instead of silently falling back.
+func newNativeProvider(name string, runner providerRunner) (ClipboardProvider, error) {
+	return nil, fmt.Errorf("sync.backend = \"native\" requires a binary built with -tags native")
+}
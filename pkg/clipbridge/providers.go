@@ -0,0 +1,390 @@
+package clipbridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ClipboardProvider reads and writes a ClipboardPayload for one endpoint of
+// the sync: a system clipboard, a tmux buffer, a remote terminal reached
+// via OSC 52, or a user-defined command pair.
+type ClipboardProvider interface {
+	Get(ctx context.Context) (ClipboardPayload, error)
+	Set(ctx context.Context, payload ClipboardPayload) error
+}
+
+// providerRunner bundles the config every built-in provider needs: a
+// per-command timeout, a size limit, and the bridge's logger.
+type providerRunner struct {
+	timeout time.Duration
+	maxSize int
+	logf    func(format string, v ...interface{})
+}
+
+// requiredBinaries lists the external tools a built-in provider shells out
+// to, so callers can check they're installed before starting the sync loop.
+func requiredBinaries(name string) []string {
+	switch name {
+	case "wayland":
+		return []string{"wl-paste", "wl-copy"}
+	case "xclip":
+		return []string{"xclip"}
+	case "xsel":
+		return []string{"xsel"}
+	case "tmux":
+		return []string{"tmux"}
+	default: // "osc52" and "custom" bring their own I/O
+		return nil
+	}
+}
+
+// newProvider constructs the named ClipboardProvider. name is one of
+// "wayland", "xclip", "xsel", "tmux", "osc52", or "custom" (configured via
+// custom).
+func newProvider(name string, custom CustomProviderConfig, runner providerRunner) (ClipboardProvider, error) {
+	switch name {
+	case "wayland":
+		return &waylandProvider{providerRunner: runner}, nil
+	case "xclip":
+		return &xclipProvider{providerRunner: runner}, nil
+	case "xsel":
+		return &xselProvider{runner}, nil
+	case "tmux":
+		return &tmuxProvider{runner}, nil
+	case "osc52":
+		return &osc52Provider{runner}, nil
+	case "custom":
+		if len(custom.GetCmd) == 0 && len(custom.SetCmd) == 0 {
+			return nil, fmt.Errorf("custom provider requires sync.custom.get_cmd and/or set_cmd")
+		}
+		return &customProvider{runner, custom.GetCmd, custom.SetCmd}, nil
+	default:
+		return nil, fmt.Errorf("unknown clipboard provider %q", name)
+	}
+}
+
+// run executes name with args under the runner's timeout and returns its stdout.
+func (r providerRunner) run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s timeout", name)
+		}
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// runWithStdin executes name with args under the runner's timeout, feeding
+// content on stdin.
+func (r providerRunner) runWithStdin(ctx context.Context, content []byte, name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(content)
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s timeout", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// primaryCapable is implemented by providers that can address the X11
+// PRIMARY selection / Wayland primary-selection protocol in addition to
+// their default CLIPBOARD selection.
+type primaryCapable interface {
+	AsPrimary() ClipboardProvider
+}
+
+// waylandProvider reads/writes the Wayland clipboard via wl-paste/wl-copy.
+// Get reads each of supportedMimeTypes that the clipboard advertises; other
+// advertised types are not read. When primary is set,
+// it addresses the Wayland primary selection instead via `--primary`.
+type waylandProvider struct {
+	providerRunner
+	primary bool
+}
+
+// AsPrimary returns a provider for the Wayland primary selection instead
+// of the regular clipboard.
+func (p *waylandProvider) AsPrimary() ClipboardProvider {
+	return &waylandProvider{providerRunner: p.providerRunner, primary: true}
+}
+
+func (p *waylandProvider) selectionArgs() []string {
+	if p.primary {
+		return []string{"--primary"}
+	}
+	return nil
+}
+
+func (p *waylandProvider) mimeTypes(ctx context.Context) []string {
+	out, err := p.run(ctx, "wl-paste", append([]string{"--list-types"}, p.selectionArgs()...)...)
+	if err != nil {
+		return nil
+	}
+	return splitLines(string(out))
+}
+
+func (p *waylandProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	types := p.mimeTypes(ctx)
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	payload := make(ClipboardPayload)
+	for _, mime := range supportedMimeTypes {
+		if !containsString(types, mime) {
+			continue
+		}
+		out, err := p.run(ctx, "wl-paste", append([]string{"-t", mime}, p.selectionArgs()...)...)
+		if err != nil {
+			p.logf("warning: wl-paste -t %s failed: %v", mime, err)
+			continue
+		}
+		if len(out) > p.maxSize {
+			p.logf("warning: wayland %s exceeds size limit (%d > %d bytes)", mime, len(out), p.maxSize)
+			continue
+		}
+		payload[mime] = out
+	}
+	return payload, nil
+}
+
+func (p *waylandProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	for mime, content := range payload {
+		if len(content) > p.maxSize {
+			p.logf("error: %s content exceeds max size, skipping wayland sync (%d > %d bytes)", mime, len(content), p.maxSize)
+			continue
+		}
+		if err := p.runWithStdin(ctx, content, "wl-copy", append([]string{"-t", mime}, p.selectionArgs()...)...); err != nil {
+			p.logf("warning: failed to set wayland %s: %v", mime, err)
+		}
+	}
+	return nil
+}
+
+// xclipProvider reads/writes an X11 selection via xclip. Get reads each of
+// supportedMimeTypes whose X11 target the selection advertises; other
+// targets are not read. When primary is set, it addresses the
+// PRIMARY selection instead of CLIPBOARD.
+type xclipProvider struct {
+	providerRunner
+	primary bool
+}
+
+// AsPrimary returns a provider for the X11 PRIMARY selection instead of
+// CLIPBOARD.
+func (p *xclipProvider) AsPrimary() ClipboardProvider {
+	return &xclipProvider{providerRunner: p.providerRunner, primary: true}
+}
+
+func (p *xclipProvider) selection() string {
+	if p.primary {
+		return "primary"
+	}
+	return "clipboard"
+}
+
+func (p *xclipProvider) targets(ctx context.Context) []string {
+	out, err := p.run(ctx, "xclip", "-selection", p.selection(), "-o", "-t", "TARGETS")
+	if err != nil {
+		return nil
+	}
+	return splitLines(string(out))
+}
+
+func (p *xclipProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	targets := p.targets(ctx)
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	payload := make(ClipboardPayload)
+	for _, mime := range supportedMimeTypes {
+		target := mimeToX11Target(mime)
+		if !containsString(targets, target) {
+			continue
+		}
+		out, err := p.run(ctx, "xclip", "-selection", p.selection(), "-o", "-t", target)
+		if err != nil {
+			p.logf("warning: xclip -t %s failed: %v", target, err)
+			continue
+		}
+		if len(out) > p.maxSize {
+			p.logf("warning: x11 %s exceeds size limit (%d > %d bytes)", mime, len(out), p.maxSize)
+			continue
+		}
+		payload[mime] = out
+	}
+	return payload, nil
+}
+
+func (p *xclipProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	for mime, content := range payload {
+		if len(content) > p.maxSize {
+			p.logf("error: %s content exceeds max size, skipping x11 sync (%d > %d bytes)", mime, len(content), p.maxSize)
+			continue
+		}
+		target := mimeToX11Target(mime)
+		if err := p.runWithStdin(ctx, content, "xclip", "-selection", p.selection(), "-t", target, "-i"); err != nil {
+			p.logf("warning: failed to set x11 %s: %v", mime, err)
+		}
+	}
+	return nil
+}
+
+// xselProvider reads/writes the X11 CLIPBOARD selection via xsel. xsel has
+// no per-MIME-type API, so only text/plain is carried.
+type xselProvider struct{ providerRunner }
+
+func (p *xselProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	out, err := p.run(ctx, "xsel", "--clipboard", "--output")
+	if err != nil || len(out) == 0 {
+		return nil, nil
+	}
+	if len(out) > p.maxSize {
+		p.logf("warning: xsel clipboard exceeds size limit (%d > %d bytes)", len(out), p.maxSize)
+		return nil, nil
+	}
+	return ClipboardPayload{"text/plain": out}, nil
+}
+
+func (p *xselProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	content, ok := payload["text/plain"]
+	if !ok {
+		return nil
+	}
+	if len(content) > p.maxSize {
+		p.logf("error: content exceeds max size, skipping xsel sync (%d > %d bytes)", len(content), p.maxSize)
+		return nil
+	}
+	if err := p.runWithStdin(ctx, content, "xsel", "--clipboard", "--input"); err != nil {
+		p.logf("warning: failed to set xsel clipboard: %v", err)
+	}
+	return nil
+}
+
+// tmuxProvider reads/writes the tmux paste buffer via save-buffer/load-buffer,
+// so the bridge can forward a clipboard into a tmux session that has no
+// direct access to the host display server.
+type tmuxProvider struct{ providerRunner }
+
+func (p *tmuxProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	out, err := p.run(ctx, "tmux", "save-buffer", "-")
+	if err != nil || len(out) == 0 {
+		return nil, nil
+	}
+	if len(out) > p.maxSize {
+		p.logf("warning: tmux buffer exceeds size limit (%d > %d bytes)", len(out), p.maxSize)
+		return nil, nil
+	}
+	return ClipboardPayload{"text/plain": out}, nil
+}
+
+func (p *tmuxProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	content, ok := payload["text/plain"]
+	if !ok {
+		return nil
+	}
+	if len(content) > p.maxSize {
+		p.logf("error: content exceeds max size, skipping tmux sync (%d > %d bytes)", len(content), p.maxSize)
+		return nil
+	}
+	if err := p.runWithStdin(ctx, content, "tmux", "load-buffer", "-"); err != nil {
+		p.logf("warning: failed to set tmux buffer: %v", err)
+	}
+	return nil
+}
+
+// osc52Provider writes the clipboard to the controlling terminal using the
+// OSC 52 escape sequence, so a local clipboard can be forwarded into a
+// remote SSH session without a shared display server. Reading is not
+// supported: terminals don't echo OSC 52 queries back reliably.
+type osc52Provider struct{ providerRunner }
+
+func (p *osc52Provider) Get(ctx context.Context) (ClipboardPayload, error) {
+	return nil, errors.New("osc52 provider does not support reading the clipboard")
+}
+
+func (p *osc52Provider) Set(ctx context.Context, payload ClipboardPayload) error {
+	content, ok := payload["text/plain"]
+	if !ok {
+		return nil
+	}
+	if len(content) > p.maxSize {
+		p.logf("error: content exceeds max size, skipping osc52 sync (%d > %d bytes)", len(content), p.maxSize)
+		return nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		p.logf("warning: failed to open /dev/tty for osc52: %v", err)
+		return nil
+	}
+	defer tty.Close()
+
+	encoded := base64.StdEncoding.EncodeToString(content)
+	if _, err := fmt.Fprintf(tty, "\x1b]52;c;%s\a", encoded); err != nil {
+		p.logf("warning: failed to write osc52 sequence: %v", err)
+	}
+	return nil
+}
+
+// customProvider shells out to user-configured yank/paste commands,
+// exchanging text/plain on stdout/stdin. This is the escape hatch for
+// backends the built-in providers don't cover.
+type customProvider struct {
+	providerRunner
+	getCmd []string
+	setCmd []string
+}
+
+func (p *customProvider) Get(ctx context.Context) (ClipboardPayload, error) {
+	if len(p.getCmd) == 0 {
+		return nil, nil
+	}
+	out, err := p.run(ctx, p.getCmd[0], p.getCmd[1:]...)
+	if err != nil || len(out) == 0 {
+		return nil, nil
+	}
+	if len(out) > p.maxSize {
+		p.logf("warning: custom get_cmd output exceeds size limit (%d > %d bytes)", len(out), p.maxSize)
+		return nil, nil
+	}
+	return ClipboardPayload{"text/plain": out}, nil
+}
+
+func (p *customProvider) Set(ctx context.Context, payload ClipboardPayload) error {
+	if len(p.setCmd) == 0 {
+		return nil
+	}
+	content, ok := payload["text/plain"]
+	if !ok {
+		return nil
+	}
+	if len(content) > p.maxSize {
+		p.logf("error: content exceeds max size, skipping custom sync (%d > %d bytes)", len(content), p.maxSize)
+		return nil
+	}
+	if err := p.runWithStdin(ctx, content, p.setCmd[0], p.setCmd[1:]...); err != nil {
+		p.logf("warning: custom set_cmd failed: %v", err)
+	}
+	return nil
+}